@@ -0,0 +1,134 @@
+package mediasort
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jpillora/media-sort/search"
+)
+
+//Program, Music and Anime extend mediasearch.MediaType with kinds
+//mediasearch itself doesn't know about - MediaType is just a string, so
+//mediasort can mint further values for its own per-kind base directories
+//and classifier rules without needing a change upstream.
+const (
+	Program mediasearch.MediaType = "program"
+	Music   mediasearch.MediaType = "music"
+	Anime   mediasearch.MediaType = "anime"
+)
+
+//ClassifyRule matches Pattern, a regexp tested against a file's base
+//name, and proposes Kind as its MediaType on a match - before
+//mediasearch.Sort ever runs, so an unambiguously-named file doesn't need
+//a network lookup just to know which base directory it belongs under.
+type ClassifyRule struct {
+	Pattern string
+	Kind    mediasearch.MediaType
+	re      *regexp.Regexp
+}
+
+//String and Set implement opts' flag.Value-style interface, so
+//--classify-rules can be given multiple times on the command line, each
+//as "kind=pattern", e.g. --classify-rules 'anime=\[.*Fansub.*\]'
+func (c ClassifyRule) String() string {
+	return fmt.Sprintf("%s=%s", c.Kind, c.Pattern)
+}
+
+func (c *ClassifyRule) Set(s string) error {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("Invalid classify rule '%s', expected 'kind=pattern'", s)
+	}
+	re, err := regexp.Compile(parts[1])
+	if err != nil {
+		return fmt.Errorf("Invalid classify pattern '%s': %s", parts[1], err)
+	}
+	c.Kind = mediasearch.MediaType(parts[0])
+	c.Pattern = parts[1]
+	c.re = re
+	return nil
+}
+
+//classifier runs Config.ClassifyRules against filenames before a file is
+//searched, and picks a winner among several matching rules using
+//Config.Precedence - e.g. a filename matching both a Program rule and a
+//Series rule should be filed as Series if Precedence lists Series first.
+type classifier struct {
+	rules      []ClassifyRule
+	precedence map[mediasearch.MediaType]int
+}
+
+//newClassifier compiles rules (if they weren't already compiled by
+//ClassifyRule.Set, e.g. when constructed directly rather than from the
+//command line) and turns precedence into a kind -> rank lookup, where a
+//lower rank wins. Kinds absent from precedence are treated as lowest
+//priority, so they only win when nothing else matches.
+func newClassifier(rules []ClassifyRule, precedence []string) (*classifier, error) {
+	for i := range rules {
+		if rules[i].re != nil {
+			continue
+		}
+		re, err := regexp.Compile(rules[i].Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid classify pattern '%s': %s", rules[i].Pattern, err)
+		}
+		rules[i].re = re
+	}
+	rank := make(map[mediasearch.MediaType]int, len(precedence))
+	for i, kind := range precedence {
+		rank[mediasearch.MediaType(kind)] = i
+	}
+	return &classifier{rules: rules, precedence: rank}, nil
+}
+
+//classify returns the MediaType of the highest-precedence rule matching
+//name, and whether any rule matched at all. A false return means the
+//classifier has no opinion, and mediasearch's own result should decide.
+func (c *classifier) classify(name string) (mediasearch.MediaType, bool) {
+	var best mediasearch.MediaType
+	bestRank := -1
+	matched := false
+	for _, rule := range c.rules {
+		if !rule.re.MatchString(name) {
+			continue
+		}
+		rank, ranked := c.precedence[rule.Kind]
+		if !ranked {
+			rank = len(c.precedence) //unranked kinds lose to any ranked one
+		}
+		if !matched || rank < bestRank {
+			best, bestRank, matched = rule.Kind, rank, true
+		}
+	}
+	return best, matched
+}
+
+//searchableKind reports whether mediasearch itself knows how to look kind
+//up (Series/Movie). The kinds the classifier above adds on top - Program,
+//Music, Anime - have no corresponding search provider, so a confident
+//classifier match for one of them can be filed without ever calling
+//mediasearch.Sort.
+func searchableKind(kind mediasearch.MediaType) bool {
+	return kind == mediasearch.Series || kind == mediasearch.Movie
+}
+
+//baseDir resolves the configured base directory for kind, including the
+//kinds the classifier above can produce in addition to mediasearch's own
+//Series/Movie.
+func (fs *fsSort) baseDir(kind mediasearch.MediaType) (string, error) {
+	switch kind {
+	case mediasearch.Series:
+		return fs.TVDir, nil
+	case mediasearch.Movie:
+		return fs.MovieDir, nil
+	case Program:
+		return fs.ProgramDir, nil
+	case Music:
+		return fs.MusicDir, nil
+	case Anime:
+		return fs.AnimeDir, nil
+	default:
+		return "", fmt.Errorf("Invalid result type: %s", kind)
+	}
+}
@@ -0,0 +1,64 @@
+package mediasort
+
+import (
+	"testing"
+
+	"github.com/jpillora/media-sort/search"
+)
+
+func TestClassifierPrecedence(t *testing.T) {
+	rules := []ClassifyRule{
+		{Pattern: `(?i)stand.?up`, Kind: Program},
+		{Pattern: `(?i)S\d+E\d+`, Kind: mediasearch.Series},
+	}
+	c, err := newClassifier(rules, []string{string(mediasearch.Series), string(Program)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//matches both rules - Series is listed first in Precedence, so it wins
+	//even though the Program rule also matched
+	if kind, ok := c.classify("Comedian.StandUp.S01E02.mkv"); !ok || kind != mediasearch.Series {
+		t.Fatalf("expected Series to win on precedence, got %q (matched=%v)", kind, ok)
+	}
+
+	//matches only the Program rule
+	if kind, ok := c.classify("Comedian.StandUp.Special.mkv"); !ok || kind != Program {
+		t.Fatalf("expected Program, got %q (matched=%v)", kind, ok)
+	}
+
+	//matches nothing - classifier has no opinion
+	if _, ok := c.classify("Some.Random.Movie.2020.mkv"); ok {
+		t.Fatalf("expected no classifier match")
+	}
+}
+
+func TestClassifierUnrankedLosesToRanked(t *testing.T) {
+	rules := []ClassifyRule{
+		{Pattern: `(?i)\.mp3$`, Kind: Music},
+		{Pattern: `(?i)anime|fansub`, Kind: Anime},
+	}
+	//Precedence only ranks Anime - Music is left unranked and should lose
+	//to any ranked kind that also matches
+	c, err := newClassifier(rules, []string{string(Anime)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind, ok := c.classify("Show.Fansub.mp3"); !ok || kind != Anime {
+		t.Fatalf("expected ranked Anime to beat unranked Music, got %q (matched=%v)", kind, ok)
+	}
+}
+
+func TestSearchableKind(t *testing.T) {
+	for kind, want := range map[mediasearch.MediaType]bool{
+		mediasearch.Series: true,
+		mediasearch.Movie:  true,
+		Program:            false,
+		Music:              false,
+		Anime:              false,
+	} {
+		if got := searchableKind(kind); got != want {
+			t.Errorf("searchableKind(%q) = %v, want %v", kind, got, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package mediasort
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+//TestApplySortDryRunLogsCompanions guards against a dry-run regression: the
+//whole point of a companion rule is that --dry-run shows what would travel
+//with the main file, so a real .srt sidecar must be logged even though
+//nothing actually moves.
+func TestApplySortDryRunLogsCompanions(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "Movie.Name.mkv")
+	sub := filepath.Join(dir, "Movie.Name.srt")
+	for _, p := range []string{src, sub} {
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := &fsSort{
+		Config: Config{
+			DryRun:     true,
+			Companions: defaultCompanions,
+		},
+		sorts:    map[string]*fileSort{},
+		sourceFS: newBasicFS(),
+		targetFS: newBasicFS(),
+	}
+	file := &fileSort{id: 1, path: src, info: info}
+	fs.sorts[src] = file
+
+	var buf bytes.Buffer
+	out := log.Writer()
+	defer log.SetOutput(out)
+	log.SetOutput(&buf)
+
+	newPath := filepath.Join(dir, "sorted", "Movie.Name.mkv")
+	if err := fs.applySort(file, &Result{}, newPath, fileFingerprint{}); err != nil {
+		t.Fatal(err)
+	}
+
+	wantCompanion := filepath.Join(dir, "sorted", "Movie.Name.srt")
+	if !strings.Contains(buf.String(), wantCompanion) {
+		t.Fatalf("expected dry-run output to mention companion %q, got:\n%s", wantCompanion, buf.String())
+	}
+	//dry-run must not actually move anything
+	if _, err := os.Stat(sub); err != nil {
+		t.Fatalf("expected companion to still exist at its original path in dry-run, got: %s", err)
+	}
+}
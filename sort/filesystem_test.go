@@ -0,0 +1,57 @@
+package mediasort
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveFileSameBackendRenames(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.mkv")
+	dst := filepath.Join(dir, "dst.mkv")
+	if err := os.WriteFile(src, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fs := newBasicFS()
+	if err := moveFile(fs, src, fs, dst); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Fatalf("expected src to be gone after a same-backend move, got err=%v", err)
+	}
+	assertFileContent(t, dst, "content")
+}
+
+//TestMoveFileCrossBackendCopiesThenRemoves exercises the fallback path in
+//moveFile: two distinct Filesystem values (even if both happen to be
+//basicFS, backed by the same disk) are treated as different backends, so
+//the move must go through Open/Create/Copy followed by Remove rather than
+//a single Rename.
+func TestMoveFileCrossBackendCopiesThenRemoves(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.mkv")
+	dst := filepath.Join(dir, "dst.mkv")
+	if err := os.WriteFile(src, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srcFS, dstFS := newBasicFS(), newBasicFS()
+	if err := moveFile(srcFS, src, dstFS, dst); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Fatalf("expected src to be removed after a cross-backend move, got err=%v", err)
+	}
+	assertFileContent(t, dst, "content")
+}
+
+func assertFileContent(t *testing.T, path, want string) {
+	t.Helper()
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Fatalf("%s: got content %q, want %q", path, got, want)
+	}
+}
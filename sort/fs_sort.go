@@ -1,20 +1,24 @@
 package mediasort
 
 import (
+	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fatih/color"
 	"github.com/jpillora/media-sort/search"
-
-	"gopkg.in/fsnotify.v1"
 )
 
 //Config is a sorter configuration
@@ -23,25 +27,81 @@ type Config struct {
 	TVDir             string   `help:"tv series base directory"`
 	MovieDir          string   `help:"movie base directory"`
 	PathConfig        `type:"embedded"`
-	Extensions        string        `help:"types of files that should be sorted"`
-	Concurrency       int           `help:"search concurrency [warning] setting this too high can cause rate-limiting errors"`
-	FileLimit         int           `help:"maximum number of files to search"`
-	Recursive         bool          `help:"also search through subdirectories"`
-	DryRun            bool          `help:"perform sort but don't actually move any files"`
-	SkipHidden        bool          `help:"skip dot files"`
-	Overwrite         bool          `help:"overwrites duplicates"`
-	OverwriteIfLarger bool          `help:"overwrites duplicates if the new file is larger"`
-	Watch             bool          `help:"watch the specified directories for changes and re-sort on change"`
-	WatchDelay        time.Duration `help:"delay before next sort after a change"`
+	Extensions        string          `help:"types of files that should be sorted"`
+	Concurrency       int             `help:"search concurrency [warning] setting this too high can cause rate-limiting errors"`
+	ScanConcurrency   int             `help:"directory scan concurrency, defaults to the number of CPUs"`
+	FileLimit         int             `help:"maximum number of files to search"`
+	Recursive         bool            `help:"also search through subdirectories"`
+	DryRun            bool            `help:"perform sort but don't actually move any files"`
+	SkipHidden        bool            `help:"skip dot files"`
+	Overwrite         bool            `help:"overwrites duplicates"`
+	OverwriteIfLarger bool            `help:"overwrites duplicates if the new file is larger"`
+	Watch             bool            `help:"watch the specified directories for changes and re-sort on change"`
+	WatchDelay        time.Duration   `help:"delay before next sort after a change"`
+	SourceFS          string          `help:"filesystem URI to scan for media files, defaults to the local disk"`
+	TargetFS          string          `help:"filesystem URI to sort media files into, defaults to --source-fs"`
+	Reindex           bool            `help:"ignore any cached sort decisions and re-run the search for every file"`
+	Companions        []CompanionRule `help:"companion files to move alongside each sorted file - a suffix (.en.srt) or glob (*.nfo) relative to the media file's basename, repeatable. Defaults to .srt"`
+	ProgramDir        string          `help:"program base directory"`
+	MusicDir          string          `help:"music base directory"`
+	AnimeDir          string          `help:"anime base directory"`
+	ClassifyRules     []ClassifyRule  `help:"classify a file by kind before searching, as 'kind=regex' matched against its basename, repeatable, e.g. anime=\\[.*Fansub.*\\]"`
+	Precedence        []string        `help:"when multiple classify rules match a filename, kinds listed earlier here win, repeatable"`
+}
+
+//CompanionRule describes a sidecar file that should travel along with a
+//sorted media file - subtitles, artwork, nfo metadata, etc - specified
+//relative to the media file's basename (its filename without extension).
+//Exactly one of Suffix or Glob is set.
+type CompanionRule struct {
+	//Suffix is appended to the basename to find an exact companion, e.g.
+	//".en.srt" matches "Movie.Name.en.srt" next to "Movie.Name.mkv"
+	Suffix string
+	//Glob, instead of an exact Suffix, is appended to the basename and
+	//matched against directory entries, for companions with variable
+	//names, e.g. Glob "-chapter-*.srt"
+	Glob string
+}
+
+//String and Set implement opts' flag.Value-style interface, so
+//--companions can be given multiple times on the command line, as either
+//a plain suffix or a glob (anything containing *, ?, or [ is a glob).
+func (c CompanionRule) String() string {
+	if c.Glob != "" {
+		return c.Glob
+	}
+	return c.Suffix
+}
+
+func (c *CompanionRule) Set(s string) error {
+	if strings.ContainsAny(s, "*?[") {
+		c.Glob = s
+	} else {
+		c.Suffix = s
+	}
+	return nil
 }
 
+//defaultCompanions is used when Config.Companions is empty, preserving
+//the single .srt sidecar mediasort always moved before CompanionRule
+//existed.
+var defaultCompanions = []CompanionRule{{Suffix: ".srt"}}
+
 //fsSort is a media sorter
 type fsSort struct {
 	Config
-	validExts map[string]bool
-	sorts     map[string]*fileSort
-	dirs      map[string]bool
-	stats     struct {
+	validExts    map[string]bool
+	sorts        map[string]*fileSort
+	dirs         map[string]bool
+	sourceFS     Filesystem
+	targetFS     Filesystem
+	fingerprints map[string]fileFingerprint
+	changed      map[string]bool
+	backoff      *backoffState
+	index        *sortIndex
+	classifier   *classifier
+	mu           sync.Mutex //guards dirs and fingerprints during concurrent scans
+	stats        struct {
 		found, matched, moved int
 	}
 }
@@ -54,6 +114,82 @@ type fileSort struct {
 	err    error
 }
 
+//fileFingerprint is a cheap signature of a file's on-disk state, used to
+//tell whether a file actually changed between watch wake-ups rather than
+//just having its containing directory touched. Fields are exported so the
+//sort index (sortIndex, gob-encoded) can persist them - gob skips
+//unexported fields silently, which would otherwise make every cached
+//entry compare unequal to itself after a save/reload round-trip.
+type fileFingerprint struct {
+	Size    int64
+	ModTime time.Time
+	Head    [sha256.Size]byte
+}
+
+//fingerprint hashes the first 64 KB of the file (via fs.sourceFS, so this
+//works for remote backends too) alongside its size and mtime, so an edit
+//that leaves size and mtime unchanged still shows up as a change, without
+//hashing the whole (possibly huge) file. A change to size or mtime alone
+//(e.g. a touch) also counts as a change.
+func (fs *fsSort) fingerprint(path string, info os.FileInfo) fileFingerprint {
+	fp := fileFingerprint{Size: info.Size(), ModTime: info.ModTime()}
+	f, err := fs.sourceFS.Open(path)
+	if err != nil {
+		return fp
+	}
+	defer f.Close()
+	h := sha256.New()
+	io.CopyN(h, f, 64*1024)
+	copy(fp.Head[:], h.Sum(nil))
+	return fp
+}
+
+//backoffState tracks consecutive sort failures across a batch so a burst
+//of errors (e.g. rate-limiting from mediasearch) backs off before the next
+//round of lookups instead of hammering the upstream API. mediasearch.Sort's
+//error isn't typed, so fail() can't tell a rate-limit response apart from
+//an ordinary "no match found" - a batch with many legitimately-unmatched
+//files will ratchet delay up to maxBackoff even without any real
+//rate-limiting. Revisit if mediasearch ever exposes a distinguishable
+//rate-limit error.
+type backoffState struct {
+	mu    sync.Mutex
+	delay time.Duration
+}
+
+const (
+	minBackoff = 500 * time.Millisecond
+	maxBackoff = 30 * time.Second
+)
+
+func (b *backoffState) wait() {
+	b.mu.Lock()
+	d := b.delay
+	b.mu.Unlock()
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
+
+func (b *backoffState) fail() {
+	b.mu.Lock()
+	switch {
+	case b.delay == 0:
+		b.delay = minBackoff
+	case b.delay*2 > maxBackoff:
+		b.delay = maxBackoff
+	default:
+		b.delay *= 2
+	}
+	b.mu.Unlock()
+}
+
+func (b *backoffState) succeed() {
+	b.mu.Lock()
+	b.delay = 0
+	b.mu.Unlock()
+}
+
 //FileSystemSort performs a media sort
 //against the file system using the provided
 //configuration
@@ -64,27 +200,97 @@ func FileSystemSort(c Config) error {
 	if c.TVDir == "" {
 		c.TVDir = "."
 	}
+	//default the classifier-only base dirs the same way, so an unset
+	//--program-dir/--music-dir/--anime-dir files into the current
+	//directory like an unset --tv-dir/--movie-dir does, rather than
+	//baseDir silently returning "" and filepath.Join dumping the file
+	//into "" (also the current directory, but not obviously so)
+	if c.ProgramDir == "" {
+		c.ProgramDir = "."
+	}
+	if c.MusicDir == "" {
+		c.MusicDir = "."
+	}
+	if c.AnimeDir == "" {
+		c.AnimeDir = "."
+	}
 	if c.Watch && !c.Recursive {
 		return errors.New("Recursive mode is required to watch directories")
 	}
 	if c.Overwrite && c.OverwriteIfLarger {
 		return errors.New("Overwrite is already specified, overwrite-if-larger is redundant")
 	}
+	if len(c.Companions) == 0 {
+		c.Companions = defaultCompanions
+	}
+	//resolve the filesystem backends to scan from and sort into - both
+	//default to the local disk, and TargetFS defaults to whatever
+	//SourceFS resolved to (the common case: sort files in place)
+	sourceFS, err := openFilesystem(c.SourceFS)
+	if err != nil {
+		return err
+	}
+	targetFS := sourceFS
+	if c.TargetFS != "" {
+		if targetFS, err = openFilesystem(c.TargetFS); err != nil {
+			return err
+		}
+	}
+	//load the cache of prior sort decisions, so re-scanning a huge
+	//library (typically in watch mode) doesn't re-run mediasearch.Sort
+	//for files that were already sorted and haven't changed since
+	indexPath, err := defaultIndexPath()
+	if err != nil {
+		return err
+	}
+	index, err := openIndex(indexPath)
+	if err != nil {
+		return err
+	}
+	if c.Reindex {
+		index.invalidate()
+	}
+	defer index.save()
+	//compile the pre-search classifier rules once up-front, so a typo'd
+	//regex is reported immediately rather than mid-scan
+	classifier, err := newClassifier(c.ClassifyRules, c.Precedence)
+	if err != nil {
+		return err
+	}
 	//init fs sort
 	fs := &fsSort{
-		Config:    c,
-		validExts: map[string]bool{},
+		Config:       c,
+		validExts:    map[string]bool{},
+		fingerprints: map[string]fileFingerprint{},
+		backoff:      &backoffState{},
+		sourceFS:     sourceFS,
+		targetFS:     targetFS,
+		index:        index,
+		classifier:   classifier,
 	}
 	for _, e := range strings.Split(c.Extensions, ",") {
 		fs.validExts["."+e] = true
 	}
+	//abort an in-flight scan cleanly on SIGINT rather than leaving
+	//goroutines running or a partially-applied sort
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			cancel()
+		}
+	}()
 	//sort loop
 	for {
-		//reset state
+		//reset per-iteration state (fingerprints and the watcher persist
+		//across iterations so we can tell new/changed files from old ones)
 		fs.sorts = map[string]*fileSort{}
 		fs.dirs = map[string]bool{}
 		//look for files
-		if err := fs.scan(); err != nil {
+		if err := fs.scan(ctx); err != nil {
 			return err
 		}
 		//ensure we have dirs to watch
@@ -95,28 +301,122 @@ func FileSystemSort(c Config) error {
 		if err := fs.sortAllFiles(); err != nil {
 			return err
 		}
+		if err := fs.index.save(); err != nil {
+			log.Printf("Warning: failed to save sort index: %s", err)
+		}
 		//watch directories
 		if !c.Watch {
 			break
 		}
-		if err := fs.watch(); err != nil {
+		if err := fs.watch(ctx); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (fs *fsSort) scan() error {
-	//scan targets for media files
+//scan walks fs.Targets for sortable media files using a pool of
+//fs.ScanConcurrency worker goroutines (default runtime.NumCPU()) that
+//drain a queue of directories, read each via fs.sourceFS, and push
+//sub-directories back onto the same queue. FileLimit is enforced
+//atomically across all workers. fileSort.id is assigned only once the
+//walk completes (sorted by path) so results stay deterministic
+//regardless of how the workers interleaved.
+func (fs *fsSort) scan(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := fs.ScanConcurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	dirQueue := make(chan string, workers*4)
+	fileQueue := make(chan *fileSort, workers*4)
+	var pending sync.WaitGroup
+	var found, matched int32 //atomic counts, shared across all workers
+
+	var errOnce sync.Once
+	var firstErr error
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	enqueueDir := func(path string) {
+		pending.Add(1)
+		select {
+		case dirQueue <- path:
+		case <-ctx.Done():
+			pending.Done()
+		}
+	}
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for dir := range dirQueue {
+				fs.scanDir(ctx, dir, enqueueDir, fileQueue, &found, &matched, fail)
+				pending.Done()
+			}
+		}()
+	}
+
+	//drain fileQueue concurrently with seeding/walking below - otherwise
+	//seeding more than fileQueue's buffer worth of top-level file Targets
+	//(nobody's reading yet) deadlocks the seeding loop forever
+	var results []*fileSort
+	drained := make(chan struct{})
+	go func() {
+		for file := range fileQueue {
+			results = append(results, file)
+		}
+		close(drained)
+	}()
+
+	//seed the queue with the targets themselves
 	for _, path := range fs.Targets {
-		info, err := os.Stat(path)
+		info, err := fs.sourceFS.Stat(path)
 		if err != nil {
 			return err
 		}
-		if err = fs.add(path, info); err != nil {
-			return err
+		if info.IsDir() {
+			if !fs.Recursive {
+				return errors.New("Recursive mode (-r) is required to sort directories")
+			}
+			enqueueDir(path)
+			continue
+		}
+		if file := fs.considerFile(path, info, &found, &matched); file != nil {
+			select {
+			case fileQueue <- file:
+			case <-ctx.Done():
+			}
 		}
 	}
+
+	go func() {
+		pending.Wait()
+		close(dirQueue)
+		close(fileQueue)
+	}()
+
+	<-drained
+	if firstErr != nil {
+		return firstErr
+	}
+
+	//number files after the walk completes, not as they're discovered,
+	//so ids are stable no matter which worker found which file
+	sort.Slice(results, func(i, j int) bool { return results[i].path < results[j].path })
+	for i, file := range results {
+		file.id = i + 1
+		fs.sorts[file.path] = file
+	}
+	fs.stats.found = int(found)
+	fs.stats.matched = len(results)
+
 	//ensure we found something
 	if len(fs.sorts) == 0 && (!fs.Watch || len(fs.dirs) == 0) {
 		return fmt.Errorf("No sortable files found (%d files checked)", fs.stats.found)
@@ -124,6 +424,92 @@ func (fs *fsSort) scan() error {
 	return nil
 }
 
+//scanDir reads a single directory and dispatches each entry: files are
+//filtered and handed to fileQueue, sub-directories are re-enqueued via
+//enqueueDir so other workers can pick them up.
+func (fs *fsSort) scanDir(ctx context.Context, dir string, enqueueDir func(string), fileQueue chan<- *fileSort, found, matched *int32, fail func(error)) {
+	if ctx.Err() != nil {
+		return
+	}
+	fs.mu.Lock()
+	fs.dirs[dir] = true
+	fs.mu.Unlock()
+	entries, err := fs.sourceFS.ReadDir(dir)
+	if err != nil {
+		fail(err)
+		return
+	}
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return
+		}
+		//skip hidden files and directories
+		if fs.SkipHidden && strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		info, err := entry.Info()
+		if err != nil {
+			fail(err)
+			return
+		}
+		if info.IsDir() {
+			if !fs.Recursive {
+				fail(errors.New("Recursive mode (-r) is required to sort directories"))
+				return
+			}
+			enqueueDir(path)
+			continue
+		}
+		if file := fs.considerFile(path, info, found, matched); file != nil {
+			select {
+			case fileQueue <- file:
+			case <-ctx.Done():
+			}
+		}
+	}
+}
+
+//considerFile applies the extension filter, FileLimit, and the
+//fingerprint/watch-changed checks that decide whether a regular file is
+//worth sorting. found/matched are shared atomic counters so FileLimit is
+//enforced consistently no matter which worker gets there first. Returns
+//nil if the file should be skipped.
+func (fs *fsSort) considerFile(path string, info os.FileInfo, found, matched *int32) *fileSort {
+	if !info.Mode().IsRegular() {
+		return nil //skip links, pipes, etc
+	}
+	atomic.AddInt32(found, 1)
+	if !fs.validExts[filepath.Ext(path)] {
+		return nil //skip invalid media file
+	}
+	//on a watch wake-up, only files the watcher actually flagged are
+	//candidates - everything else was found by directory recursion only
+	if fs.changed != nil && !fs.changed[path] {
+		return nil
+	}
+	//skip files whose fingerprint (size+mtime+content head) hasn't
+	//changed since the last time we saw them - avoids re-sorting
+	//files that were already sorted or are untouched
+	fp := fs.fingerprint(path, info)
+	fs.mu.Lock()
+	prev, seen := fs.fingerprints[path]
+	fs.mu.Unlock()
+	if seen && prev == fp {
+		return nil
+	}
+	if atomic.AddInt32(matched, 1) > int32(fs.FileLimit) {
+		//don't record the fingerprint for a file FileLimit dropped -
+		//otherwise it looks "unchanged" on the next scan and never
+		//gets another chance once the limit isn't in its way
+		return nil //FileLimit reached
+	}
+	fs.mu.Lock()
+	fs.fingerprints[path] = fp
+	fs.mu.Unlock()
+	return &fileSort{path: path, info: info}
+}
+
 func (fs *fsSort) sortAllFiles() error {
 	//perform sort
 	if fs.DryRun {
@@ -149,104 +535,102 @@ func (fs *fsSort) sortAllFiles() error {
 	return nil
 }
 
-func (fs *fsSort) watch() error {
+//watch blocks until fs.sourceFS reports a batch of relevant changes under
+//fs.dirs, then returns so the caller can re-scan. Delegating to the
+//Filesystem means backends without inotify (e.g. SFTP) can fall back to
+//polling without fsSort needing to know the difference.
+func (fs *fsSort) watch(ctx context.Context) error {
 	if len(fs.dirs) == 0 {
 		return errors.New("No directories to watch")
 	}
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		return fmt.Errorf("Failed to create file watcher: %s", err)
-	}
-	for dir, _ := range fs.dirs {
-		if err := watcher.Add(dir); err != nil {
-			return fmt.Errorf("Failed to watch directory: %s", err)
-		}
-		log.Printf("Watching %s for changes...", color.CyanString(dir))
+	dirs := make([]string, 0, len(fs.dirs))
+	for dir := range fs.dirs {
+		dirs = append(dirs, dir)
 	}
-	select {
-	case <-watcher.Events:
-	case <-watcher.Errors:
+	changed, err := fs.sourceFS.Watch(ctx, dirs, fs.WatchDelay, func(ext string) bool { return fs.validExts[ext] })
+	if err != nil {
+		return err
 	}
-	go watcher.Close()
-	log.Printf("Change detected, re-sorting in %s...", fs.WatchDelay)
-	time.Sleep(fs.WatchDelay)
+	log.Printf("%d change(s) detected, re-sorting...", len(changed))
+	fs.changed = changed
 	return nil
 }
 
-func (fs *fsSort) add(path string, info os.FileInfo) error {
-	//skip hidden files and directories
-	if fs.SkipHidden && strings.HasPrefix(info.Name(), ".") {
-		return nil
-	}
-	//limit recursion depth
-	if len(fs.sorts) >= fs.FileLimit {
-		return nil
-	}
-	//add regular files (non-symlinks)
-	if info.Mode().IsRegular() {
-		fs.stats.found++
-		if !fs.validExts[filepath.Ext(path)] {
-			return nil //skip invalid media file
+//sortFile resolves where file.path should end up - from the sort index
+//if a cached decision still points at a destination that exists, from the
+//pre-search classifier if it recognises the filename, or otherwise via a
+//fresh mediasearch.Sort - and then applies it.
+func (fs *fsSort) sortFile(file *fileSort) error {
+	fp := fs.fingerprints[file.path]
+	if entry, ok := fs.index.lookup(file.path, fp); ok {
+		if _, err := fs.targetFS.Stat(entry.Dest); err == nil {
+			return fs.applySort(file, entry.Result, entry.Dest, fp)
 		}
-		fs.sorts[path] = &fileSort{id: len(fs.sorts) + 1, path: path, info: info}
-		fs.stats.matched++
-		return nil
+		//cached destination is gone (moved/deleted out-of-band) - fall
+		//through and re-search as if this were a fresh file
 	}
-	//recurse into directories
-	if info.IsDir() {
-		if !fs.Recursive {
-			return errors.New("Recursive mode (-r) is required to sort directories")
-		}
-		//note directory
-		fs.dirs[path] = true
-		//add all files in dir
-		infos, err := ioutil.ReadDir(path)
+	//run the local classifier against the filename alone before ever
+	//touching the network - a confident match for a kind mediasearch
+	//doesn't know how to look up (Program/Music/Anime) is filed straight
+	//away, so an unambiguously-named file never costs a TVDB/OMDb lookup
+	//or a backoff slot
+	if kind, ok := fs.classifier.classify(filepath.Base(file.path)); ok && !searchableKind(kind) {
+		baseDir, err := fs.baseDir(kind)
 		if err != nil {
 			return err
 		}
-		for _, info := range infos {
-			p := filepath.Join(path, info.Name())
-			//recurse
-			if err := fs.add(p, info); err != nil {
-				return err
-			}
-		}
+		newPath := filepath.Join(baseDir, filepath.Base(file.path))
+		return fs.applySort(file, &Result{MType: string(kind)}, newPath, fp)
 	}
-	//skip links,pipes,etc
-	return nil
-}
-
-func (fs *fsSort) sortFile(file *fileSort) error {
+	fs.backoff.wait()
 	result, err := Sort(file.path)
 	if err != nil {
+		fs.backoff.fail()
 		return err
 	}
+	fs.backoff.succeed()
 	newPath, err := result.PrettyPath(fs.PathConfig)
 	if err != nil {
 		return err
 	}
-	baseDir := ""
-	switch mediasearch.MediaType(result.MType) {
-	case mediasearch.Series:
-		baseDir = fs.TVDir
-	case mediasearch.Movie:
-		baseDir = fs.MovieDir
-	default:
-		return fmt.Errorf("Invalid result type: %s", result.MType)
+	//mediasearch's own guess can still be ambiguous (e.g. a stand-up
+	//special that parses as a Movie) - when the local classifier has an
+	//opinion on the filename, it wins over result.MType
+	kind := mediasearch.MediaType(result.MType)
+	if classified, ok := fs.classifier.classify(filepath.Base(file.path)); ok {
+		kind = classified
+	}
+	baseDir, err := fs.baseDir(kind)
+	if err != nil {
+		return err
 	}
 	newPath = filepath.Join(baseDir, newPath)
+	return fs.applySort(file, result, newPath, fp)
+}
+
+//applySort logs, and (unless DryRun) moves, file.path to newPath. After a
+//successful move, or when file.path already is newPath, it records the
+//decision in the sort index keyed by newPath so a later re-scan of the
+//destination recognises it as already sorted; a dry run or a refused
+//overwrite records nothing.
+func (fs *fsSort) applySort(file *fileSort, result *Result, newPath string, fp fileFingerprint) error {
 	//DEBUG
-	// log.Printf("SUCCESS = D%d #%d\n  %s\n  %s", r.Distance, len(query), query, r.Title)
-	log.Printf("[#%d/%d] %s\n  └─> %s", file.id, len(fs.sorts), color.GreenString(result.Path), color.GreenString(newPath))
+	//log.Printf("SUCCESS = D%d #%d\n  %s\n  %s", r.Distance, len(query), query, r.Title)
+	log.Printf("[#%d/%d] %s\n  └─> %s", file.id, len(fs.sorts), color.GreenString(file.path), color.GreenString(newPath))
+	if file.path == newPath {
+		fs.index.store(newPath, fp, result, newPath)
+		return nil //already sorted
+	}
 	if fs.DryRun {
+		//moveCompanions logs (and, since fs.DryRun, skips moving) each
+		//companion it finds, so dry-run output shows exactly what would
+		//travel with the main file
+		fs.moveCompanions(file, newPath)
 		return nil //dont actually move
 	}
-	if result.Path == newPath {
-		return nil //already sorted
-	}
 
 	//check already exists
-	if newInfo, err := os.Stat(newPath); err == nil {
+	if newInfo, err := fs.targetFS.Stat(newPath); err == nil {
 		newIsLarger := newInfo.Size() > file.info.Size()
 		overwrite := fs.Overwrite
 		if !overwrite && fs.OverwriteIfLarger && newIsLarger {
@@ -257,20 +641,85 @@ func (fs *fsSort) sortFile(file *fileSort) error {
 		}
 	}
 	//mkdir -p
-	err = os.MkdirAll(filepath.Dir(newPath), 0755)
+	err := fs.targetFS.MkdirAll(filepath.Dir(newPath), 0755)
 	if err != nil {
 		return err //failed to mkdir
 	}
-	//mv
-	err = os.Rename(result.Path, newPath)
+	//mv (same-backend rename, or a copy+remove across backends)
+	err = moveFile(fs.sourceFS, file.path, fs.targetFS, newPath)
 	if err != nil {
 		return err //failed to move
 	}
-	//if .srt file exists for the file, mv it too
-	pathSubs := strings.TrimSuffix(result.Path, filepath.Ext(result.Path)) + ".srt"
-	if _, err := os.Stat(pathSubs); err == nil {
-		newPathSubs := strings.TrimSuffix(newPath, filepath.Ext(newPath)) + ".srt"
-		os.Rename(pathSubs, newPathSubs) //best-effort
-	}
+	fs.index.remove(file.path)
+	fs.index.store(newPath, fp, result, newPath)
+	fs.moveCompanions(file, newPath)
 	return nil
 }
+
+//moveCompanions finds and moves every companion file (subtitles, nfo,
+//artwork, ...) that belongs alongside file.path, preserving each one's
+//suffix relative to the media basename on the destination side. Failures
+//are logged but don't fail the overall sort - a missing/unmovable
+//companion shouldn't leave the main file stranded.
+func (fs *fsSort) moveCompanions(file *fileSort, newPath string) {
+	base := strings.TrimSuffix(filepath.Base(file.path), filepath.Ext(file.path))
+	companions, err := fs.findCompanions(file.path, base)
+	if err != nil {
+		log.Printf("  └─> %s", color.RedString("Failed to scan for companion files: %s", err))
+		return
+	}
+	newBase := strings.TrimSuffix(newPath, filepath.Ext(newPath))
+	for _, companionPath := range companions {
+		suffix := strings.TrimPrefix(filepath.Base(companionPath), base)
+		newCompanionPath := newBase + suffix
+		log.Printf("  └─> %s", color.GreenString(newCompanionPath))
+		if fs.DryRun {
+			continue //dont actually move
+		}
+		if newInfo, err := fs.targetFS.Stat(newCompanionPath); err == nil {
+			companionInfo, err := fs.sourceFS.Stat(companionPath)
+			newIsLarger := err == nil && newInfo.Size() > companionInfo.Size()
+			overwrite := fs.Overwrite || (fs.OverwriteIfLarger && newIsLarger)
+			if !overwrite {
+				log.Printf("  └─> %s", color.RedString("File already exists '%s' (try setting --overwrite)", newCompanionPath))
+				continue
+			}
+		}
+		if err := moveFile(fs.sourceFS, companionPath, fs.targetFS, newCompanionPath); err != nil {
+			log.Printf("  └─> %s", color.RedString("Failed to move companion file: %s", err))
+		}
+	}
+}
+
+//findCompanions matches fs.Companions against the directory containing
+//srcPath, returning the companion files that actually exist.
+func (fs *fsSort) findCompanions(srcPath, base string) ([]string, error) {
+	dir := filepath.Dir(srcPath)
+	var matches []string
+	var entries []os.DirEntry
+	for _, rule := range fs.Companions {
+		if rule.Suffix != "" {
+			candidate := filepath.Join(dir, base+rule.Suffix)
+			if _, err := fs.sourceFS.Stat(candidate); err == nil {
+				matches = append(matches, candidate)
+			}
+			continue
+		}
+		if rule.Glob == "" {
+			continue
+		}
+		if entries == nil {
+			var err error
+			if entries, err = fs.sourceFS.ReadDir(dir); err != nil {
+				return nil, err
+			}
+		}
+		pattern := base + rule.Glob
+		for _, entry := range entries {
+			if ok, _ := filepath.Match(pattern, entry.Name()); ok {
+				matches = append(matches, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+	return matches, nil
+}
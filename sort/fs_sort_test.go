@@ -0,0 +1,47 @@
+package mediasort
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+//TestConsiderFileFileLimitRetried guards against a file that's dropped only
+//because FileLimit was already exceeded being fingerprinted as "seen" -
+//such a file must still look new/changed on a later scan once the limit
+//isn't in its way.
+func TestConsiderFileFileLimitRetried(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "movie.mkv")
+	if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := &fsSort{
+		Config:       Config{FileLimit: 0},
+		validExts:    map[string]bool{".mkv": true},
+		fingerprints: map[string]fileFingerprint{},
+		sourceFS:     newBasicFS(),
+	}
+
+	var found, matched int32
+	if file := fs.considerFile(path, info, &found, &matched); file != nil {
+		t.Fatalf("expected considerFile to drop %s once FileLimit is exceeded, got %+v", path, file)
+	}
+	if _, seen := fs.fingerprints[path]; seen {
+		t.Fatalf("considerFile must not fingerprint a file it dropped for FileLimit, or it'll never be retried")
+	}
+
+	//raise the limit and retry, as a later scan/watch wake-up would
+	fs.FileLimit = 1
+	atomic.StoreInt32(&matched, 0)
+	file := fs.considerFile(path, info, &found, &matched)
+	if file == nil {
+		t.Fatalf("expected %s to be retried once FileLimit no longer excludes it", path)
+	}
+}
@@ -0,0 +1,128 @@
+package mediasort
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+//indexEntry is a cached sort decision: the fingerprint the source file
+//had at the time it was sorted, the mediasearch Result that produced it,
+//and the destination path it was moved to.
+type indexEntry struct {
+	Fingerprint fileFingerprint
+	Result      *Result
+	Dest        string
+}
+
+//sortIndex is a gob-encoded, on-disk cache of prior sort decisions keyed
+//by source path. Re-scanning a large library (most often watch mode
+//re-walking a whole tree) can then skip mediasearch.Sort entirely for
+//files that were already sorted and haven't changed since.
+type sortIndex struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]indexEntry
+	dirty   bool
+}
+
+//defaultIndexPath returns $XDG_CACHE_HOME/media-sort/index.db, falling
+//back to os.UserCacheDir() when XDG_CACHE_HOME isn't set.
+func defaultIndexPath() (string, error) {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		var err error
+		if dir, err = os.UserCacheDir(); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(dir, "media-sort", "index.db"), nil
+}
+
+//openIndex loads path if it exists, or starts with an empty index if
+//this is the first run.
+func openIndex(path string) (*sortIndex, error) {
+	idx := &sortIndex{path: path, entries: map[string]indexEntry{}}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return idx, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := gob.NewDecoder(f).Decode(&idx.entries); err != nil {
+		return nil, fmt.Errorf("Corrupt sort index '%s': %s", path, err)
+	}
+	return idx, nil
+}
+
+//lookup returns the cached entry for path, but only if it was recorded
+//against the same fingerprint - anything else means the file has changed
+//since and the cache entry no longer applies.
+func (idx *sortIndex) lookup(path string, fp fileFingerprint) (indexEntry, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	entry, ok := idx.entries[path]
+	if !ok || entry.Fingerprint != fp {
+		return indexEntry{}, false
+	}
+	return entry, true
+}
+
+func (idx *sortIndex) store(path string, fp fileFingerprint, result *Result, dest string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.entries[path] = indexEntry{Fingerprint: fp, Result: result, Dest: dest}
+	idx.dirty = true
+}
+
+//remove drops any cached entry for path, called once a file has
+//successfully moved away from it so the index doesn't keep pointing
+//search results at a path that no longer exists.
+func (idx *sortIndex) remove(path string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if _, ok := idx.entries[path]; ok {
+		delete(idx.entries, path)
+		idx.dirty = true
+	}
+}
+
+//invalidate drops every cached entry, backing --reindex.
+func (idx *sortIndex) invalidate() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.entries = map[string]indexEntry{}
+	idx.dirty = true
+}
+
+//save persists the index to disk, if it has changed since the last save.
+func (idx *sortIndex) save() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if !idx.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0755); err != nil {
+		return err
+	}
+	tmp := idx.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(idx.entries); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, idx.path); err != nil {
+		return err
+	}
+	idx.dirty = false
+	return nil
+}
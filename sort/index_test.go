@@ -0,0 +1,68 @@
+package mediasort
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSortIndexRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	idx, err := openIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fp := fileFingerprint{Size: 123}
+	result := &Result{MType: "series"}
+	idx.store("/src/show.mkv", fp, result, "/dst/show.mkv")
+	if err := idx.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	//re-open from disk - a fresh sortIndex must see what the previous one stored
+	reopened, err := openIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, ok := reopened.lookup("/src/show.mkv", fp)
+	if !ok {
+		t.Fatal("expected lookup to find the persisted entry")
+	}
+	if entry.Dest != "/dst/show.mkv" || entry.Result.MType != "series" {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+
+	//a different fingerprint for the same path means the file changed -
+	//the cached decision must not apply
+	if _, ok := reopened.lookup("/src/show.mkv", fileFingerprint{Size: 999}); ok {
+		t.Fatal("lookup must reject a stale fingerprint")
+	}
+
+	reopened.remove("/src/show.mkv")
+	if _, ok := reopened.lookup("/src/show.mkv", fp); ok {
+		t.Fatal("expected remove to drop the cached entry")
+	}
+}
+
+func TestSortIndexInvalidate(t *testing.T) {
+	idx, err := openIndex(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp := fileFingerprint{Size: 1}
+	idx.store("/a", fp, &Result{}, "/b")
+	idx.invalidate()
+	if _, ok := idx.lookup("/a", fp); ok {
+		t.Fatal("expected invalidate to drop every cached entry")
+	}
+}
+
+func TestOpenIndexMissingFileIsEmpty(t *testing.T) {
+	idx, err := openIndex(filepath.Join(t.TempDir(), "does-not-exist", "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.entries) != 0 {
+		t.Fatalf("expected an empty index, got %d entries", len(idx.entries))
+	}
+}
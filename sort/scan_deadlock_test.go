@@ -0,0 +1,40 @@
+package mediasort
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScanManyFileTargetsDoesNotDeadlock(t *testing.T) {
+	dir := t.TempDir()
+	var targets []string
+	for i := 0; i < 200; i++ {
+		p := filepath.Join(dir, fmt.Sprintf("f%03d.mkv", i))
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		targets = append(targets, p)
+	}
+	fs := &fsSort{
+		Config:       Config{Targets: targets, FileLimit: 10000, ScanConcurrency: 2},
+		validExts:    map[string]bool{".mkv": true},
+		fingerprints: map[string]fileFingerprint{},
+		sourceFS:     newBasicFS(),
+	}
+	fs.sorts = map[string]*fileSort{}
+	fs.dirs = map[string]bool{}
+	done := make(chan error, 1)
+	go func() { done <- fs.scan(context.Background()) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("scan deadlocked on file Targets exceeding the fileQueue buffer")
+	}
+}